@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// importGroupNames names the three groups, in the order ImportOrderRule
+// expects them to appear within a file.
+var importGroupNames = []string{"standard library", "third-party", "local"}
+
+const (
+	importGroupStdlib = iota
+	importGroupThirdParty
+	importGroupLocal
+)
+
+// checkImportOrder enforces rule against every .go file of pkg, requiring
+// imports to be grouped standard library, third-party, then local, with
+// each group blank-line separated and sorted. ruleFile is the
+// .import-restrictions file rule was declared in, reported on every
+// resulting Violation.
+func checkImportOrder(pkg *packages.Package, rule ImportOrderRule, ruleFile string) (violations []Violation) {
+	var localPrefixes []string
+	for _, p := range strings.Split(rule.LocalPrefixes, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			localPrefixes = append(localPrefixes, p)
+		}
+	}
+
+	for _, f := range pkg.Syntax {
+		filename := pkg.Fset.Position(f.Pos()).Filename
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.IMPORT {
+				continue
+			}
+			violations = append(violations, checkImportGenDecl(pkg, filename, ruleFile, gen, localPrefixes)...)
+		}
+	}
+	return violations
+}
+
+// importGroup is a run of import specs separated from its neighbors by a
+// blank line, along with the group (stdlib/third-party/local) it belongs
+// to, as classified from its first import.
+type importGroup struct {
+	kind  int
+	specs []*ast.ImportSpec
+}
+
+func checkImportGenDecl(pkg *packages.Package, filename, ruleFile string, gen *ast.GenDecl, localPrefixes []string) (violations []Violation) {
+	var groups []importGroup
+	prevLine := -1
+	for _, spec := range gen.Specs {
+		imp, ok := spec.(*ast.ImportSpec)
+		if !ok {
+			continue
+		}
+		path := importPath(imp)
+		start := imp.Pos()
+		if imp.Doc != nil {
+			// a comment above an import belongs to it, not a group break
+			start = imp.Doc.Pos()
+		}
+		line := pkg.Fset.Position(start).Line
+		if len(groups) == 0 || line > prevLine+1 {
+			groups = append(groups, importGroup{kind: classifyImport(path, localPrefixes)})
+		}
+		g := &groups[len(groups)-1]
+		g.specs = append(g.specs, imp)
+		prevLine = pkg.Fset.Position(imp.End()).Line
+	}
+
+	maxKind := -1
+	for _, g := range groups {
+		if g.kind < maxKind {
+			violations = append(violations, importOrderViolation(pkg, filename, ruleFile, g.specs[0], KindImportGroupOrder,
+				fmt.Sprintf("import %q is in the wrong group; expected group order: %s", importPath(g.specs[0]), strings.Join(importGroupNames, ", "))))
+		}
+		if g.kind > maxKind {
+			maxKind = g.kind
+		}
+
+		for i, spec := range g.specs {
+			if kind := classifyImport(importPath(spec), localPrefixes); kind != g.kind {
+				violations = append(violations, importOrderViolation(pkg, filename, ruleFile, spec, KindImportMixedGroup,
+					fmt.Sprintf("import %q belongs to the %s group, not the %s group it's grouped with; add a blank line to separate it",
+						importPath(spec), importGroupNames[kind], importGroupNames[g.kind])))
+			}
+			if i == 0 {
+				continue
+			}
+			prev, cur := importPath(g.specs[i-1]), importPath(spec)
+			if cur < prev {
+				violations = append(violations, importOrderViolation(pkg, filename, ruleFile, spec, KindImportNotSorted,
+					fmt.Sprintf("import %q is not sorted within its group, expected before %q", cur, prev)))
+			}
+		}
+	}
+	return violations
+}
+
+func importOrderViolation(pkg *packages.Package, filename, ruleFile string, spec *ast.ImportSpec, kind ViolationKind, message string) Violation {
+	pos := pkg.Fset.Position(spec.Pos())
+	return Violation{
+		Package:  pkg.PkgPath,
+		RuleFile: ruleFile,
+		Import:   importPath(spec),
+		Kind:     kind,
+		Position: &pos,
+		Message:  fmt.Sprintf("%s:%d: %s", filename, pos.Line, message),
+	}
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return spec.Path.Value
+	}
+	return path
+}
+
+// classifyImport buckets path into the standard library, third-party, or
+// local group. A path is local when it matches one of localPrefixes;
+// otherwise it's standard library when its first path component has no
+// dot (the same heuristic goimports uses), and third-party otherwise.
+func classifyImport(path string, localPrefixes []string) int {
+	for _, prefix := range localPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return importGroupLocal
+		}
+	}
+	first := path
+	if i := strings.Index(path, "/"); i >= 0 {
+		first = path[:i]
+	}
+	if !strings.Contains(first, ".") {
+		return importGroupStdlib
+	}
+	return importGroupThirdParty
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const mixedGroupNoBlankLine = `package sample
+
+import (
+	"fmt"
+	"github.com/acme/foo"
+)
+`
+
+const properlyGroupedImports = `package sample
+
+import (
+	"fmt"
+
+	"github.com/acme/foo"
+)
+`
+
+func parsePackage(t *testing.T, pkgPath, src string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "sample.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return &packages.Package{
+		PkgPath: pkgPath,
+		Fset:    fset,
+		Syntax:  []*ast.File{f},
+	}
+}
+
+func TestCheckImportOrderFlagsMixedGroupWithoutBlankLine(t *testing.T) {
+	pkg := parsePackage(t, "base/sample", mixedGroupNoBlankLine)
+
+	violations := checkImportOrder(pkg, ImportOrderRule{}, "base/.import-restrictions")
+	if len(violations) != 1 {
+		t.Fatalf("expected one violation for a stdlib/third-party import crammed into one block, got %v", violations)
+	}
+	if violations[0].Kind != KindImportMixedGroup {
+		t.Fatalf("expected %s, got %s: %s", KindImportMixedGroup, violations[0].Kind, violations[0].Message)
+	}
+	if violations[0].Import != "github.com/acme/foo" {
+		t.Fatalf("expected violation to point at the third-party import, got %q", violations[0].Import)
+	}
+	if violations[0].RuleFile != "base/.import-restrictions" {
+		t.Fatalf("expected violation to report the rule file it came from, got %q", violations[0].RuleFile)
+	}
+}
+
+func TestCheckImportOrderAcceptsProperlyGroupedImports(t *testing.T) {
+	pkg := parsePackage(t, "base/sample", properlyGroupedImports)
+
+	violations := checkImportOrder(pkg, ImportOrderRule{}, "base/.import-restrictions")
+	if len(violations) != 0 {
+		t.Fatalf("expected no violations for correctly grouped imports, got %v", violations)
+	}
+}
+
+func TestClassifyImport(t *testing.T) {
+	local := []string{"github.com/me/proj"}
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"fmt", importGroupStdlib},
+		{"encoding/json", importGroupStdlib},
+		{"github.com/acme/foo", importGroupThirdParty},
+		{"github.com/me/proj/pkg/a", importGroupLocal},
+	}
+	for _, tt := range tests {
+		if got := classifyImport(tt.path, local); got != tt.want {
+			t.Errorf("classifyImport(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -18,6 +20,15 @@ const FileName = ".import-restrictions"
 
 type Rules struct {
 	Rules []Rule
+
+	// InverseRules constrain who may import the package declaring this rule
+	// file, mirroring the Kubernetes import-boss "inverse rule" design.
+	// SelectorRegexp is matched against the importer's package path.
+	InverseRules []InverseRule
+
+	// ImportOrderRules enforce goimports-style import grouping within each
+	// .go file of the package.
+	ImportOrderRules []ImportOrderRule
 }
 
 type Rule struct {
@@ -26,34 +37,164 @@ type Rule struct {
 	ForbiddenPrefixes []string
 }
 
+type InverseRule struct {
+	SelectorRegexp    string
+	AllowedPrefixes   []string
+	ForbiddenPrefixes []string
+}
+
+// ImportOrderRule enforces that each .go file's imports are grouped into
+// standard library, third-party, and local imports, in that order, blank
+// line separated and sorted within each group, mirroring "goimports -local".
+type ImportOrderRule struct {
+	// LocalPrefixes is a comma-separated list of import path prefixes
+	// treated as the local group, mirroring goimports' "-local" flag.
+	LocalPrefixes string
+}
+
+// ruleEntry, inverseRuleEntry and importOrderRuleEntry pair a rule loaded
+// from a .import-restrictions file with the path to that file, so a
+// Violation can always report where the rule it's complaining about was
+// actually declared, even when GetRuleFileForPackage inherited it from an
+// ancestor directory.
+type ruleEntry struct {
+	Rule
+	ruleFile string
+}
+
+type inverseRuleEntry struct {
+	InverseRule
+	ruleFile string
+}
+
+type importOrderRuleEntry struct {
+	ImportOrderRule
+	ruleFile string
+}
+
+// mergedRules is the result of merging every .import-restrictions file from
+// a package's directory up to base, keeping track of which file each rule
+// came from.
+type mergedRules struct {
+	Rules            []ruleEntry
+	InverseRules     []inverseRuleEntry
+	ImportOrderRules []importOrderRuleEntry
+}
+
 type Verifier struct {
-	base      string
-	cfg       packages.Config
-	mapPkgs   map[string]*packages.Package
-	ruleFiles map[string]*Rules
+	base       string
+	cfg        packages.Config
+	mapPkgs    map[string]*packages.Package
+	ruleFiles  map[string]*mergedRules
+	dirRules   map[string]*Rules
+	transitive bool
+}
+
+// Option configures optional Verifier behavior.
+type Option func(*Verifier)
+
+// WithTransitive makes Verify walk each package's full transitive import
+// graph instead of only its direct imports, reporting the shortest import
+// chain to each violation.
+func WithTransitive(transitive bool) Option {
+	return func(v *Verifier) {
+		v.transitive = transitive
+	}
+}
+
+// WithTags sets the build tags (as passed to "go build -tags") used to
+// load packages, so files gated behind "//go:build" constraints can be
+// verified under each tag combination.
+func WithTags(tags string) Option {
+	return func(v *Verifier) {
+		if tags != "" {
+			v.cfg.BuildFlags = append(v.cfg.BuildFlags, "-tags="+tags)
+		}
+	}
+}
+
+// WithMod sets the "-mod" flag (for example "mod", "vendor", "readonly")
+// passed to the go command used to load packages.
+func WithMod(mod string) Option {
+	return func(v *Verifier) {
+		if mod != "" {
+			v.cfg.BuildFlags = append(v.cfg.BuildFlags, "-mod="+mod)
+		}
+	}
+}
+
+// WithOverlay makes LoadPackages load packages against an in-memory overlay,
+// mapping filenames to replacement file contents, following the
+// go/packages.Config.Overlay convention. This lets verify-imports run
+// against unsaved editor buffers, e.g. from an LSP-driven pre-commit hook.
+func WithOverlay(overlay map[string][]byte) Option {
+	return func(v *Verifier) {
+		v.cfg.Overlay = overlay
+	}
 }
 
-func New(base string, dir string) *Verifier {
+// loadOverlay reads a JSON file mapping filenames to replacement file
+// contents and converts it into the map[string][]byte form expected by
+// packages.Config.Overlay.
+func loadOverlay(path string) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("overlay file %q: %v", path, err)
+	}
+	overlay := make(map[string][]byte, len(raw))
+	for name, content := range raw {
+		overlay[name] = []byte(content)
+	}
+	return overlay, nil
+}
+
+func New(base string, dir string, opts ...Option) *Verifier {
 	if strings.HasSuffix(base, "/") {
 		base = base[:len(base)-1]
 	}
 	cfg := packages.Config{
-		Mode: packages.LoadImports,
+		// LoadSyntax also loads each package's AST, which is needed to
+		// report the token.Position of offending import statements.
+		Mode: packages.LoadSyntax,
 		Dir:  dir,
 	}
-	return &Verifier{
+	v := &Verifier{
 		base:      base,
 		cfg:       cfg,
 		mapPkgs:   make(map[string]*packages.Package),
-		ruleFiles: make(map[string]*Rules),
+		ruleFiles: make(map[string]*mergedRules),
+		dirRules:  make(map[string]*Rules),
+	}
+	for _, opt := range opts {
+		opt(v)
 	}
+	if v.transitive {
+		// Without NeedDeps, imported packages are placeholders whose own
+		// Imports are empty, so the transitive walk would stop at depth one.
+		v.cfg.Mode |= packages.NeedDeps
+	}
+	return v
 }
 
+// LoadPackages accepts the same pattern forms as cmd/go: plain import paths,
+// "..." wildcards (for example "github.com/me/proj/..."), "./..." and other
+// local directory patterns, and absolute directory paths. Patterns are
+// resolved against v.cfg.Dir and the enclosing module's go.mod so they can
+// be checked against base; patterns that expand outside base are rejected.
 func (v *Verifier) LoadPackages(patterns ...string) error {
 	base2 := v.base + "/"
 	for _, p := range patterns {
-		if p != v.base && !strings.HasPrefix(p, base2) {
-			return fmt.Errorf("pattern must start with base, but %q does not start with %q", p, base2)
+		resolved, err := v.resolvePattern(p)
+		if err != nil {
+			return err
+		}
+		prefix := strings.TrimSuffix(strings.TrimSuffix(resolved, "..."), "/")
+		if prefix != v.base && !strings.HasPrefix(prefix, base2) {
+			return fmt.Errorf("pattern %q (resolved to %q) must start with base, but does not start with %q", p, resolved, base2)
 		}
 	}
 
@@ -67,7 +208,72 @@ func (v *Verifier) LoadPackages(patterns ...string) error {
 	return nil
 }
 
-func (v *Verifier) GetRuleFileForPackage(pkgPath string) (rules *Rules, err error) {
+// resolvePattern converts a local (".", "./...", "./foo", "../foo/...") or
+// absolute directory pattern into the import path it denotes, so it can be
+// validated against base the same way a plain import path pattern is.
+// Import path patterns, including those already ending in "/...", are
+// returned unchanged.
+func (v *Verifier) resolvePattern(pattern string) (string, error) {
+	if pattern != "." && pattern != ".." && !strings.HasPrefix(pattern, "./") && !strings.HasPrefix(pattern, "../") && !filepath.IsAbs(pattern) {
+		return pattern, nil
+	}
+
+	wildcard := strings.HasSuffix(pattern, "...")
+	rel := strings.TrimSuffix(pattern, "...")
+	rel = strings.TrimSuffix(rel, "/")
+
+	dir := rel
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(v.cfg.Dir, dir)
+	}
+
+	modPath, modDir, err := findModule(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving pattern %q: %v", pattern, err)
+	}
+	relDir, err := filepath.Rel(modDir, dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving pattern %q: %v", pattern, err)
+	}
+
+	importPath := modPath
+	if relDir != "." {
+		importPath = modPath + "/" + filepath.ToSlash(relDir)
+	}
+	if wildcard {
+		importPath += "/..."
+	}
+	return importPath, nil
+}
+
+// findModule walks up from dir looking for a go.mod, returning the module
+// path it declares and the directory it lives in.
+func findModule(dir string) (modPath string, modDir string, err error) {
+	for d := dir; ; {
+		data, err := ioutil.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if strings.HasPrefix(line, "module ") {
+					return strings.TrimSpace(strings.TrimPrefix(line, "module ")), d, nil
+				}
+			}
+			return "", "", fmt.Errorf("go.mod %q has no module directive", filepath.Join(d, "go.mod"))
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", "", fmt.Errorf("no go.mod found above %q", dir)
+		}
+		d = parent
+	}
+}
+
+// GetRuleFileForPackage returns the rules that apply to pkgPath, merging the
+// rule files found at every directory from pkgPath up to base, so that a
+// rule declared near the module root applies to every package beneath it in
+// addition to any more specific rule file closer to pkgPath. Each returned
+// rule remembers the actual .import-restrictions file it was loaded from.
+func (v *Verifier) GetRuleFileForPackage(pkgPath string) (rules *mergedRules, err error) {
 	// only return package under base path
 	if !strings.HasPrefix(pkgPath, v.base) {
 		return nil, nil
@@ -81,7 +287,45 @@ func (v *Verifier) GetRuleFileForPackage(pkgPath string) (rules *Rules, err erro
 		v.ruleFiles[pkgPath] = rules
 	}()
 
-	// try loading from disk
+	merged := mergedRules{}
+	found := false
+	for dir := pkgPath; ; dir = filepath.Dir(dir) {
+		r, err := v.loadRuleFileInDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			ruleFile := filepath.Join(dir, FileName)
+			for _, rule := range r.Rules {
+				merged.Rules = append(merged.Rules, ruleEntry{Rule: rule, ruleFile: ruleFile})
+			}
+			for _, ir := range r.InverseRules {
+				merged.InverseRules = append(merged.InverseRules, inverseRuleEntry{InverseRule: ir, ruleFile: ruleFile})
+			}
+			for _, ior := range r.ImportOrderRules {
+				merged.ImportOrderRules = append(merged.ImportOrderRules, importOrderRuleEntry{ImportOrderRule: ior, ruleFile: ruleFile})
+			}
+			found = true
+		}
+		if dir == v.base {
+			break
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return &merged, nil
+}
+
+// loadRuleFileInDir loads the FileName rule file directly inside the
+// directory backing pkgPath, returning (nil, nil) when no rule file is
+// present there. Results are cached per directory, since the same ancestor
+// rule file is looked up once for every package beneath it.
+func (v *Verifier) loadRuleFileInDir(pkgPath string) (*Rules, error) {
+	if r, ok := v.dirRules[pkgPath]; ok {
+		return r, nil
+	}
+
 	relPath := pkgPath[len(v.base):]
 	dirPath := filepath.Join(v.cfg.Dir, relPath)
 	fi, err := os.Stat(dirPath)
@@ -93,10 +337,16 @@ func (v *Verifier) GetRuleFileForPackage(pkgPath string) (rules *Rules, err erro
 	}
 
 	path := filepath.Join(dirPath, FileName)
-	if _, err = os.Stat(path); err == nil {
-		return loadRuleFile(path)
+	if _, err := os.Stat(path); err != nil {
+		v.dirRules[pkgPath] = nil
+		return nil, nil
 	}
-	return v.GetRuleFileForPackage(filepath.Dir(pkgPath))
+	r, err := loadRuleFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v.dirRules[pkgPath] = r
+	return r, nil
 }
 
 func loadRuleFile(path string) (*Rules, error) {
@@ -113,87 +363,225 @@ func loadRuleFile(path string) (*Rules, error) {
 	return &r, nil
 }
 
-func (v *Verifier) VerifyPackage(pkg *packages.Package) (errs []error) {
+func (v *Verifier) VerifyPackage(pkg *packages.Package) (violations []Violation, errs []error) {
 	rules, err := v.GetRuleFileForPackage(pkg.PkgPath)
 	if err != nil {
-		return []error{err}
+		return nil, []error{err}
 	}
 	if rules == nil {
-		return nil // skip the package
+		return nil, nil // skip the package
 	}
 
-	actualPath := filepath.Join(pkg.PkgPath, FileName)
+	chains := v.importChains(pkg)
 	for _, r := range rules.Rules {
 		re, err := regexp.Compile(r.SelectorRegexp)
 		if err != nil {
-			err = fmt.Errorf("regexp `%s` in file %q doesn't compile: %v", r.SelectorRegexp, actualPath, err)
+			err = fmt.Errorf("regexp `%s` in file %q doesn't compile: %v", r.SelectorRegexp, r.ruleFile, err)
 			errs = append(errs, err)
 			continue
 		}
-		for v := range pkg.Imports {
-			if !re.MatchString(v) {
+		for path, chain := range chains {
+			if !re.MatchString(path) {
 				continue
 			}
+			suffix := ""
+			if v.transitive {
+				suffix = fmt.Sprintf(" (via %s)", strings.Join(chain, " -> "))
+			}
+			pos := findImportPosition(pkg, chain[1])
 			for _, forbidden := range r.ForbiddenPrefixes {
-				if strings.HasPrefix(v, forbidden) {
-					err = fmt.Errorf("import %q has forbidden prefix %v", v, forbidden)
-					errs = append(errs, err)
-					continue
+				if strings.HasPrefix(path, forbidden) {
+					violations = append(violations, Violation{
+						Package:  pkg.PkgPath,
+						RuleFile: r.ruleFile,
+						Selector: r.SelectorRegexp,
+						Import:   path,
+						Kind:     KindForbiddenPrefix,
+						Chain:    chain,
+						Position: pos,
+						Message:  fmt.Sprintf("import %q has forbidden prefix %v%s", path, forbidden, suffix),
+					})
 				}
 			}
 			found := false
 			for _, allowed := range r.AllowedPrefixes {
-				if strings.HasPrefix(v, allowed) {
+				if strings.HasPrefix(path, allowed) {
 					found = true
 					break
 				}
 			}
 			if !found {
-				err := fmt.Errorf("import %q did not match any allowed prefix", v)
+				violations = append(violations, Violation{
+					Package:  pkg.PkgPath,
+					RuleFile: r.ruleFile,
+					Selector: r.SelectorRegexp,
+					Import:   path,
+					Kind:     KindNotInAllowed,
+					Chain:    chain,
+					Position: pos,
+					Message:  fmt.Sprintf("import %q did not match any allowed prefix%s", path, suffix),
+				})
+			}
+		}
+	}
+	invViolations, invErrs := v.verifyInverseRules(pkg, chains)
+	violations = append(violations, invViolations...)
+	errs = append(errs, invErrs...)
+
+	for _, r := range rules.ImportOrderRules {
+		violations = append(violations, checkImportOrder(pkg, r.ImportOrderRule, r.ruleFile)...)
+	}
+	return violations, errs
+}
+
+// findImportPosition returns the source position of the import statement
+// for importPath within pkg, or nil if pkg's syntax wasn't loaded or it
+// doesn't directly import importPath.
+func findImportPosition(pkg *packages.Package, importPath string) *token.Position {
+	for _, f := range pkg.Syntax {
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || path != importPath {
+				continue
+			}
+			pos := pkg.Fset.Position(imp.Pos())
+			return &pos
+		}
+	}
+	return nil
+}
+
+// importChains returns, for every import reachable from pkg, the shortest
+// chain of import paths from pkg to it. With v.transitive set this walks
+// the full transitive import graph; otherwise it only reports pkg's direct
+// imports, each as a chain of length two.
+func (v *Verifier) importChains(pkg *packages.Package) map[string][]string {
+	if !v.transitive {
+		chains := make(map[string][]string, len(pkg.Imports))
+		for path := range pkg.Imports {
+			chains[path] = []string{pkg.PkgPath, path}
+		}
+		return chains
+	}
+
+	chains := make(map[string][]string)
+	type node struct {
+		pkg   *packages.Package
+		chain []string
+	}
+	queue := []node{{pkg, []string{pkg.PkgPath}}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for path, imp := range cur.pkg.Imports {
+			if _, ok := chains[path]; ok {
+				continue
+			}
+			chain := append(append([]string{}, cur.chain...), path)
+			chains[path] = chain
+			queue = append(queue, node{imp, chain})
+		}
+	}
+	return chains
+}
+
+// verifyInverseRules checks pkg against the InverseRules declared by every
+// package it imports (directly, or transitively when v.transitive is set),
+// so a leaf package can restrict who is allowed to depend on it without
+// every consumer having to declare a matching rule. chains is the result
+// of v.importChains(pkg), reused here to avoid recomputing it.
+func (v *Verifier) verifyInverseRules(pkg *packages.Package, chains map[string][]string) (violations []Violation, errs []error) {
+	for impPath, chain := range chains {
+		impRules, err := v.GetRuleFileForPackage(impPath)
+		if err != nil || impRules == nil {
+			continue
+		}
+		pos := findImportPosition(pkg, chain[1])
+		suffix := ""
+		if v.transitive {
+			suffix = fmt.Sprintf(" (via %s)", strings.Join(chain, " -> "))
+		}
+		for _, r := range impRules.InverseRules {
+			re, err := regexp.Compile(r.SelectorRegexp)
+			if err != nil {
+				err = fmt.Errorf("inverse rule regexp `%s` in file %q doesn't compile: %v", r.SelectorRegexp, r.ruleFile, err)
 				errs = append(errs, err)
+				continue
+			}
+			if !re.MatchString(pkg.PkgPath) {
+				continue
+			}
+			for _, forbidden := range r.ForbiddenPrefixes {
+				if strings.HasPrefix(pkg.PkgPath, forbidden) {
+					violations = append(violations, Violation{
+						Package:  pkg.PkgPath,
+						RuleFile: r.ruleFile,
+						Selector: r.SelectorRegexp,
+						Import:   impPath,
+						Kind:     KindInverseForbiddenPrefix,
+						Chain:    chain,
+						Position: pos,
+						Message:  fmt.Sprintf("package %q is forbidden from importing %q by its inverse rule prefix %v%s", pkg.PkgPath, impPath, forbidden, suffix),
+					})
+				}
+			}
+			if len(r.AllowedPrefixes) == 0 {
+				continue
+			}
+			found := false
+			for _, allowed := range r.AllowedPrefixes {
+				if strings.HasPrefix(pkg.PkgPath, allowed) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				violations = append(violations, Violation{
+					Package:  pkg.PkgPath,
+					RuleFile: r.ruleFile,
+					Selector: r.SelectorRegexp,
+					Import:   impPath,
+					Kind:     KindInverseNotInAllowed,
+					Chain:    chain,
+					Position: pos,
+					Message:  fmt.Sprintf("package %q importing %q did not match any allowed prefix in its inverse rule%s", pkg.PkgPath, impPath, suffix),
+				})
 			}
 		}
 	}
-	return errs
+	return violations, errs
 }
 
-func (v *Verifier) Verify() error {
+// Verify checks every loaded package against its rules and returns the
+// structured result, which the caller is free to render in whatever
+// format it needs (see FormatText, FormatJSON, FormatSARIF).
+func (v *Verifier) Verify() *Result {
 	paths := make([]string, 0, len(v.mapPkgs))
 	for pkgPath := range v.mapPkgs {
 		paths = append(paths, pkgPath)
 	}
 	sort.Strings(paths)
 
-	ok := true
+	result := &Result{}
 	for _, pkgPath := range paths {
 		pkg := v.mapPkgs[pkgPath]
-		errs := v.VerifyPackage(pkg)
-		if errs != nil {
-			ok = false
-			fmt.Printf("Package %q\n", pkgPath)
-			for i, err := range errs {
-				fmt.Printf("\t%v\n", err)
-				if i >= 9 && len(errs) > i {
-					fmt.Printf("\t... total %v imports violated\n", len(errs))
-					break
-				}
-			}
-			fmt.Println()
+		violations, errs := v.VerifyPackage(pkg)
+		result.Violations = append(result.Violations, violations...)
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
 		}
 	}
-	if !ok {
-		return fmt.Errorf("some packages violate import rules")
-	}
-	return nil
+	return result
 }
 
 func main() {
 	flag.Usage = func() {
-		fmt.Println(`Usage of verify-imports:
+		fmt.Print(`Usage of verify-imports:
 	verify-import -base BASE -dir DIR PATTERN ...
 
 Example:
 	verify-import -base github.com/me/myproject github.com/me/myproject/cmd/... github.com/me/myproject/pkg/...
+	verify-import -base github.com/me/myproject ./...
 `)
 		flag.PrintDefaults()
 	}
@@ -202,6 +590,11 @@ Example:
 	must("unexpected", err)
 	flBase := flag.String("base", "", "Base package path (for example: github.com/me/myproject)")
 	flDir := flag.String("dir", cdir, "The module directory (contains go.mod, default to working directory)")
+	flTransitive := flag.Bool("transitive", false, "Also check each rule against the full transitive import graph, not just direct imports")
+	flFormat := flag.String("format", "text", "Report format: text, json, or sarif")
+	flTags := flag.String("tags", "", "Comma-separated build tags to pass to the go command")
+	flOverlay := flag.String("overlay", "", "Path to a JSON file mapping filenames to replacement contents (go/packages overlay convention)")
+	flMod := flag.String("mod", "", "-mod flag passed to the go command (for example mod, vendor, readonly)")
 	flag.Parse()
 	patterns := flag.Args()
 
@@ -210,10 +603,39 @@ Example:
 		os.Exit(1)
 	}
 
-	v := New(*flBase, *flDir)
+	opts := []Option{WithTransitive(*flTransitive), WithTags(*flTags), WithMod(*flMod)}
+	if *flOverlay != "" {
+		overlay, err := loadOverlay(*flOverlay)
+		must("load overlay:", err)
+		opts = append(opts, WithOverlay(overlay))
+	}
+
+	v := New(*flBase, *flDir, opts...)
 	must("load packages:", v.LoadPackages(patterns...))
-	must("verify imports:", v.Verify())
-	fmt.Println("\nâœ“ ok")
+	result := v.Verify()
+
+	switch *flFormat {
+	case "text":
+		fmt.Print(FormatText(result))
+	case "json":
+		out, err := FormatJSON(result)
+		must("format json:", err)
+		fmt.Println(out)
+	case "sarif":
+		out, err := FormatSARIF(result, *flDir)
+		must("format sarif:", err)
+		fmt.Println(out)
+	default:
+		fmt.Printf("unknown -format %q, want text, json, or sarif\n", *flFormat)
+		os.Exit(1)
+	}
+
+	if !result.OK() {
+		os.Exit(1)
+	}
+	if *flFormat == "text" {
+		fmt.Println("\nâœ“ ok")
+	}
 }
 
 func must(msg string, err error) {
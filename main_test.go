@@ -0,0 +1,228 @@
+package main
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// newTestVerifier builds a Verifier whose per-directory rule-file cache is
+// pre-populated, so tests can exercise verifyInverseRules without touching
+// disk. GetRuleFileForPackage still runs for real, merging these on top of
+// each other as it would for rule files read from disk.
+func newTestVerifier(transitive bool, dirRules map[string]*Rules) *Verifier {
+	v := New("base", "/nonexistent", WithTransitive(transitive))
+	for pkgPath, rules := range dirRules {
+		v.dirRules[pkgPath] = rules
+	}
+	return v
+}
+
+func TestVerifyInverseRulesTransitive(t *testing.T) {
+	pkgC := &packages.Package{PkgPath: "base/c", Fset: token.NewFileSet()}
+	pkgB := &packages.Package{
+		PkgPath: "base/b",
+		Fset:    token.NewFileSet(),
+		Imports: map[string]*packages.Package{"base/c": pkgC},
+	}
+	pkgA := &packages.Package{
+		PkgPath: "base/a",
+		Fset:    token.NewFileSet(),
+		Imports: map[string]*packages.Package{"base/b": pkgB},
+	}
+
+	// base/c only allows base/b to depend on it.
+	rulesC := &Rules{
+		InverseRules: []InverseRule{{
+			SelectorRegexp:  ".*",
+			AllowedPrefixes: []string{"base/b"},
+		}},
+	}
+	ruleFiles := map[string]*Rules{"base": nil, "base/a": nil, "base/b": nil, "base/c": rulesC}
+
+	t.Run("direct dependent within AllowedPrefixes is fine", func(t *testing.T) {
+		v := newTestVerifier(true, ruleFiles)
+		violations, errs := v.verifyInverseRules(pkgB, v.importChains(pkgB))
+		if len(errs) != 0 || len(violations) != 0 {
+			t.Fatalf("expected no violations for base/b importing base/c, got violations=%v errs=%v", violations, errs)
+		}
+	})
+
+	t.Run("non-transitive mode cannot see the indirect dependent", func(t *testing.T) {
+		v := newTestVerifier(false, ruleFiles)
+		violations, errs := v.verifyInverseRules(pkgA, v.importChains(pkgA))
+		if len(errs) != 0 {
+			t.Fatalf("unexpected errs: %v", errs)
+		}
+		if len(violations) != 0 {
+			t.Fatalf("without -transitive, base/a's direct imports don't include base/c, expected no violations, got %v", violations)
+		}
+	})
+
+	t.Run("transitive mode flags the indirect dependent", func(t *testing.T) {
+		v := newTestVerifier(true, ruleFiles)
+		violations, errs := v.verifyInverseRules(pkgA, v.importChains(pkgA))
+		if len(errs) != 0 {
+			t.Fatalf("unexpected errs: %v", errs)
+		}
+		if len(violations) != 1 {
+			t.Fatalf("expected exactly one violation for base/a -> base/b -> base/c, got %v", violations)
+		}
+		if violations[0].Kind != KindInverseNotInAllowed {
+			t.Fatalf("expected %s, got %s: %s", KindInverseNotInAllowed, violations[0].Kind, violations[0].Message)
+		}
+	})
+}
+
+func TestImportChains(t *testing.T) {
+	pkgC := &packages.Package{PkgPath: "base/c", Fset: token.NewFileSet()}
+	pkgB := &packages.Package{
+		PkgPath: "base/b",
+		Fset:    token.NewFileSet(),
+		Imports: map[string]*packages.Package{"base/c": pkgC},
+	}
+	pkgA := &packages.Package{
+		PkgPath: "base/a",
+		Fset:    token.NewFileSet(),
+		Imports: map[string]*packages.Package{"base/b": pkgB},
+	}
+
+	t.Run("non-transitive only reports direct imports", func(t *testing.T) {
+		v := New("base", "/nonexistent", WithTransitive(false))
+		chains := v.importChains(pkgA)
+		if len(chains) != 1 {
+			t.Fatalf("expected only base/a's direct import, got %v", chains)
+		}
+		if got := chains["base/b"]; len(got) != 2 || got[0] != "base/a" || got[1] != "base/b" {
+			t.Fatalf("expected chain [base/a base/b], got %v", got)
+		}
+	})
+
+	t.Run("transitive walks the whole import graph", func(t *testing.T) {
+		v := New("base", "/nonexistent", WithTransitive(true))
+		chains := v.importChains(pkgA)
+		if len(chains) != 2 {
+			t.Fatalf("expected both base/b and base/c to be reachable, got %v", chains)
+		}
+		want := []string{"base/a", "base/b", "base/c"}
+		got := chains["base/c"]
+		if len(got) != len(want) {
+			t.Fatalf("expected shortest chain %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected shortest chain %v, got %v", want, got)
+			}
+		}
+	})
+}
+
+// writeTestModule creates a go.mod declaring modPath under a fresh temp
+// directory and returns that directory.
+func writeTestModule(t *testing.T, modPath string) string {
+	t.Helper()
+	dir := t.TempDir()
+	content := "module " + modPath + "\n\ngo 1.21\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+	return dir
+}
+
+func TestFindModule(t *testing.T) {
+	dir := writeTestModule(t, "github.com/me/proj")
+	sub := filepath.Join(dir, "pkg", "a")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	modPath, modDir, err := findModule(sub)
+	if err != nil {
+		t.Fatalf("findModule: %v", err)
+	}
+	if modPath != "github.com/me/proj" {
+		t.Fatalf("expected module path github.com/me/proj, got %q", modPath)
+	}
+	if modDir != dir {
+		t.Fatalf("expected module dir %q, got %q", dir, modDir)
+	}
+}
+
+func TestFindModuleNotFound(t *testing.T) {
+	if _, _, err := findModule(t.TempDir()); err == nil {
+		t.Fatal("expected an error when no go.mod exists above dir")
+	}
+}
+
+func TestResolvePattern(t *testing.T) {
+	dir := writeTestModule(t, "github.com/me/proj")
+	sub := filepath.Join(dir, "pkg", "a")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	v := New("github.com/me/proj", sub)
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"./...", "github.com/me/proj/pkg/a/..."},
+		{"../...", "github.com/me/proj/pkg/..."},
+		{"github.com/me/proj/pkg/b/...", "github.com/me/proj/pkg/b/..."},
+	}
+	for _, tt := range tests {
+		got, err := v.resolvePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("resolvePattern(%q): %v", tt.pattern, err)
+		}
+		if got != tt.want {
+			t.Errorf("resolvePattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestOptionsConfigurePackagesConfig(t *testing.T) {
+	v := New("base", "/nonexistent", WithTags("integration"), WithMod("vendor"))
+	wantFlags := []string{"-tags=integration", "-mod=vendor"}
+	if len(v.cfg.BuildFlags) != len(wantFlags) {
+		t.Fatalf("expected BuildFlags %v, got %v", wantFlags, v.cfg.BuildFlags)
+	}
+	for i, want := range wantFlags {
+		if v.cfg.BuildFlags[i] != want {
+			t.Errorf("BuildFlags[%d] = %q, want %q", i, v.cfg.BuildFlags[i], want)
+		}
+	}
+
+	overlay := map[string][]byte{"a.go": []byte("package a")}
+	v2 := New("base", "/nonexistent", WithOverlay(overlay))
+	if v2.cfg.Overlay["a.go"] == nil {
+		t.Fatal("expected WithOverlay to populate cfg.Overlay")
+	}
+}
+
+func TestWithTagsAndWithModOmitEmptyValues(t *testing.T) {
+	v := New("base", "/nonexistent", WithTags(""), WithMod(""))
+	if len(v.cfg.BuildFlags) != 0 {
+		t.Fatalf("expected no BuildFlags for empty -tags/-mod, got %v", v.cfg.BuildFlags)
+	}
+}
+
+func TestLoadOverlay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(path, []byte(`{"a.go": "package a\n"}`), 0o644); err != nil {
+		t.Fatalf("write overlay file: %v", err)
+	}
+
+	overlay, err := loadOverlay(path)
+	if err != nil {
+		t.Fatalf("loadOverlay: %v", err)
+	}
+	if string(overlay["a.go"]) != "package a\n" {
+		t.Fatalf("expected overlay contents for a.go, got %q", overlay["a.go"])
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ViolationKind classifies why an import was flagged, matching the
+// vocabulary used by import-boss-style tools.
+type ViolationKind string
+
+const (
+	KindForbiddenPrefix        ViolationKind = "forbidden-prefix"
+	KindNotInAllowed           ViolationKind = "not-in-allowed"
+	KindInverseForbiddenPrefix ViolationKind = "inverse-forbidden-prefix"
+	KindInverseNotInAllowed    ViolationKind = "inverse-not-in-allowed"
+	KindImportGroupOrder       ViolationKind = "import-group-order"
+	KindImportNotSorted        ViolationKind = "import-not-sorted"
+	KindImportMixedGroup       ViolationKind = "import-mixed-group"
+)
+
+// Violation is a single structured rule violation produced by Verify.
+type Violation struct {
+	Package  string          `json:"package"`
+	RuleFile string          `json:"ruleFile"`
+	Selector string          `json:"selector"`
+	Import   string          `json:"import"`
+	Kind     ViolationKind   `json:"kind"`
+	Chain    []string        `json:"chain,omitempty"`
+	Message  string          `json:"message"`
+	Position *token.Position `json:"position,omitempty"`
+}
+
+// Result is the structured outcome of Verify, covering both rule
+// violations and errors encountered while evaluating rules (such as a
+// malformed regexp) that aren't violations themselves.
+type Result struct {
+	Violations []Violation `json:"violations"`
+	Errors     []string    `json:"errors,omitempty"`
+}
+
+// OK reports whether the verification found nothing to complain about.
+func (r *Result) OK() bool {
+	return len(r.Violations) == 0 && len(r.Errors) == 0
+}
+
+// FormatText renders result the way Verify used to print directly to
+// stdout: violations grouped by package, capped at ten lines each.
+func FormatText(result *Result) string {
+	var b strings.Builder
+
+	byPkg := map[string][]Violation{}
+	var order []string
+	for _, v := range result.Violations {
+		if _, ok := byPkg[v.Package]; !ok {
+			order = append(order, v.Package)
+		}
+		byPkg[v.Package] = append(byPkg[v.Package], v)
+	}
+	sort.Strings(order)
+
+	for _, pkgPath := range order {
+		vs := byPkg[pkgPath]
+		fmt.Fprintf(&b, "Package %q\n", pkgPath)
+		for i, v := range vs {
+			fmt.Fprintf(&b, "\t%v\n", v.Message)
+			if i >= 9 && len(vs) > i+1 {
+				fmt.Fprintf(&b, "\t... total %v imports violated\n", len(vs))
+				break
+			}
+		}
+		b.WriteString("\n")
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(&b, "%v\n", e)
+	}
+	return b.String()
+}
+
+// FormatJSON renders result as indented JSON.
+func FormatJSON(result *Result) (string, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sarifLog and friends are the minimal subset of the SARIF 2.1.0 schema
+// needed to surface each violation as a CI code-scanning annotation.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// FormatSARIF renders result as a SARIF 2.1.0 log, one result per
+// violation, so CI systems like GitHub code scanning can annotate the
+// importing file/line directly. rootDir is the directory SARIF artifact
+// URIs are made relative to (typically the module directory passed as
+// -dir); a file outside rootDir, or a rootDir of "", falls back to its
+// absolute path.
+func FormatSARIF(result *Result, rootDir string) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "verify-imports"}},
+	}
+	for _, v := range result.Violations {
+		res := sarifResult{
+			RuleID:  string(v.Kind),
+			Level:   "error",
+			Message: sarifMessage{Text: v.Message},
+		}
+		if v.Position != nil {
+			res.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: sarifURI(v.Position.Filename, rootDir)},
+					Region: sarifRegion{
+						StartLine:   v.Position.Line,
+						StartColumn: v.Position.Column,
+					},
+				},
+			}}
+		}
+		run.Results = append(run.Results, res)
+	}
+	log := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sarifURI makes filename relative to rootDir, using filepath.ToSlash since
+// SARIF URIs are forward-slash paths regardless of host OS. Falls back to
+// filename unchanged if rootDir is empty or filename isn't beneath it.
+func sarifURI(filename, rootDir string) string {
+	if rootDir == "" {
+		return filepath.ToSlash(filename)
+	}
+	rel, err := filepath.Rel(rootDir, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(filename)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func sampleResult() *Result {
+	pos := token.Position{Filename: "/root/proj/pkg/a/a.go", Line: 5, Column: 2}
+	return &Result{
+		Violations: []Violation{{
+			Package:  "base/pkg/a",
+			RuleFile: "base/pkg/.import-restrictions",
+			Selector: "base/pkg/b",
+			Import:   "base/pkg/b",
+			Kind:     KindNotInAllowed,
+			Chain:    []string{"base/pkg/a", "base/pkg/b"},
+			Position: &pos,
+			Message:  `pkg/a/a.go:5: import "base/pkg/b" did not match any allowed prefix`,
+		}},
+		Errors: []string{"some rule file error"},
+	}
+}
+
+func TestFormatJSONRoundTrips(t *testing.T) {
+	result := sampleResult()
+	out, err := FormatJSON(result)
+	if err != nil {
+		t.Fatalf("FormatJSON: %v", err)
+	}
+
+	var got Result
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal FormatJSON output: %v", err)
+	}
+	if len(got.Violations) != 1 || got.Violations[0].Import != "base/pkg/b" {
+		t.Fatalf("round-tripped result lost its violation: %+v", got)
+	}
+	if len(got.Errors) != 1 || got.Errors[0] != "some rule file error" {
+		t.Fatalf("round-tripped result lost its error: %+v", got)
+	}
+}
+
+func TestFormatSARIFMakesURIsRelativeToRootDir(t *testing.T) {
+	result := sampleResult()
+	out, err := FormatSARIF(result, "/root/proj")
+	if err != nil {
+		t.Fatalf("FormatSARIF: %v", err)
+	}
+	if !strings.Contains(out, `"uri": "pkg/a/a.go"`) {
+		t.Fatalf("expected SARIF URI relative to rootDir, got:\n%s", out)
+	}
+	if strings.Contains(out, "/root/proj") {
+		t.Fatalf("expected no absolute paths left in SARIF output, got:\n%s", out)
+	}
+}
+
+func TestFormatSARIFFallsBackToAbsoluteOutsideRootDir(t *testing.T) {
+	result := sampleResult()
+	out, err := FormatSARIF(result, "/elsewhere")
+	if err != nil {
+		t.Fatalf("FormatSARIF: %v", err)
+	}
+	if !strings.Contains(out, `"uri": "/root/proj/pkg/a/a.go"`) {
+		t.Fatalf("expected SARIF URI to fall back to the absolute path when outside rootDir, got:\n%s", out)
+	}
+}